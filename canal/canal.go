@@ -0,0 +1,60 @@
+package canal
+
+import (
+	"sync"
+
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+)
+
+// Canal streams a MySQL/MariaDB binlog and dispatches the events it
+// sees to the handlers callers register.
+type Canal struct {
+	cfg *Config
+
+	master *MasterInfo
+	syncer *replication.BinlogSyncer
+
+	masterInfoHandler MasterInfoHandler
+
+	// pendingGTIDSet accumulates the GTID of the transaction currently
+	// being streamed; it is only promoted to master's committed GTID
+	// set on XIDEvent; see commitGTID.
+	pendingGTIDSet mysql.GTIDSet
+
+	// eventHandlers holds, per event type, the chain of handlers
+	// installed by RegisterEventHandler (preceded by the defaults from
+	// initDefaultEventHandlers). eventHandlersOnce guards lazily
+	// installing those defaults exactly once.
+	eventHandlers     map[replication.EventType][]EventHandlerFunc
+	eventHandlersOnce sync.Once
+
+	// ddlParser extracts DDLEvents from QueryEvents; see SetDDLParser
+	// and getDDLParser, which lazily defaults it to regexDDLParser.
+	ddlParser        DDLParser
+	ddlEventHandlers []DDLEventHandler
+
+	transactionHandlers []TransactionHandler
+
+	// currentTxn is the transaction in progress between the last BEGIN
+	// (or the first buffered row, in GTID-mode autocommit) and its
+	// XIDEvent; nil when Config.UseTransaction is false or none is
+	// open. See appendTransactionRowsEvent/commitTransaction.
+	currentTxn *Transaction
+
+	stats           statsState
+	metricsReporter MetricsReporter
+
+	notifyCh   chan error
+	notifyOnce sync.Once
+}
+
+func (c *Canal) getMasterInfoHandler() MasterInfoHandler {
+	return c.masterInfoHandler
+}
+
+// SetMasterInfoHandler installs h to persist the binlog position (and, in
+// GTID mode, GTID set) whenever MasterInfo.Save reports it due.
+func (c *Canal) SetMasterInfoHandler(h MasterInfoHandler) {
+	c.masterInfoHandler = h
+}
@@ -0,0 +1,40 @@
+package canal
+
+import (
+	"time"
+
+	"github.com/siddontang/go-mysql/mysql"
+)
+
+// DumpConfig controls canal's mysqldump-based full sync, performed once
+// before it switches to streaming the binlog.
+type DumpConfig struct {
+	// TableDB restricts the dump (and the row events canal reacts to
+	// while streaming) to a single schema; empty means no restriction.
+	TableDB string
+}
+
+// Config holds the settings a Canal is constructed with.
+type Config struct {
+	Addr     string
+	User     string
+	Password string
+
+	Dump DumpConfig
+
+	// Flavor selects the GTID encoding canal should parse and emit:
+	// mysql.MySQLFlavor or mysql.MariaDBFlavor. Only meaningful when
+	// resuming/waiting by GTID rather than (file, offset).
+	Flavor mysql.GTIDFlavor
+
+	// UseTransaction buffers row events between BEGIN and XIDEvent and
+	// delivers them as a single Transaction to TransactionHandlers,
+	// instead of delivering each row individually to RowsEventHandlers.
+	UseTransaction bool
+
+	// HeartbeatPeriod is the master's configured HEARTBEAT_EVENT
+	// interval (SET @master_heartbeat_period). It's only used to judge
+	// whether the master has stalled; see checkMasterStalled. Leave
+	// zero to disable stalled-master detection.
+	HeartbeatPeriod time.Duration
+}
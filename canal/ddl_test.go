@@ -0,0 +1,160 @@
+package canal
+
+import "testing"
+
+func TestRegexDDLParserParse(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		query  string
+		want   []*DDLEvent
+	}{
+		{
+			name:   "create table unqualified",
+			schema: "test",
+			query:  "CREATE TABLE foo (id INT)",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "CREATE TABLE foo (id INT)", Action: CreateTableAction},
+			},
+		},
+		{
+			name:   "create table if not exists qualified",
+			schema: "test",
+			query:  "CREATE TABLE IF NOT EXISTS `other`.`foo` (id INT)",
+			want: []*DDLEvent{
+				{Schema: "other", Table: "foo", Statement: "CREATE TABLE IF NOT EXISTS `other`.`foo` (id INT)", Action: CreateTableAction},
+			},
+		},
+		{
+			name:   "drop table single",
+			schema: "test",
+			query:  "DROP TABLE foo",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "DROP TABLE foo", Action: DropTableAction},
+			},
+		},
+		{
+			name:   "drop table if exists multiple qualified",
+			schema: "test",
+			query:  "DROP TABLE IF EXISTS foo, other.bar",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "DROP TABLE IF EXISTS foo, other.bar", Action: DropTableAction},
+				{Schema: "other", Table: "bar", Statement: "DROP TABLE IF EXISTS foo, other.bar", Action: DropTableAction},
+			},
+		},
+		{
+			name:   "truncate table",
+			schema: "test",
+			query:  "TRUNCATE TABLE foo",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "TRUNCATE TABLE foo", Action: TruncateTableAction},
+			},
+		},
+		{
+			name:   "truncate without table keyword",
+			schema: "test",
+			query:  "TRUNCATE foo",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "TRUNCATE foo", Action: TruncateTableAction},
+			},
+		},
+		{
+			name:   "truncate table qualified",
+			schema: "test",
+			query:  "TRUNCATE TABLE `other`.`foo`",
+			want: []*DDLEvent{
+				{Schema: "other", Table: "foo", Statement: "TRUNCATE TABLE `other`.`foo`", Action: TruncateTableAction},
+			},
+		},
+		{
+			name:   "rename table single pair",
+			schema: "test",
+			query:  "RENAME TABLE foo TO bar",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", NewSchema: "test", NewTable: "bar", Statement: "RENAME TABLE foo TO bar", Action: RenameTableAction},
+			},
+		},
+		{
+			name:   "rename table multiple pairs qualified",
+			schema: "test",
+			query:  "RENAME TABLE foo TO bar, other.a TO other.b",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", NewSchema: "test", NewTable: "bar", Statement: "RENAME TABLE foo TO bar, other.a TO other.b", Action: RenameTableAction},
+				{Schema: "other", Table: "a", NewSchema: "other", NewTable: "b", Statement: "RENAME TABLE foo TO bar, other.a TO other.b", Action: RenameTableAction},
+			},
+		},
+		{
+			name:   "create index",
+			schema: "test",
+			query:  "CREATE INDEX idx_foo ON foo (id)",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "CREATE INDEX idx_foo ON foo (id)", Action: CreateIndexAction},
+			},
+		},
+		{
+			name:   "create index qualified",
+			schema: "test",
+			query:  "CREATE INDEX idx_foo ON `other`.`foo` (id)",
+			want: []*DDLEvent{
+				{Schema: "other", Table: "foo", Statement: "CREATE INDEX idx_foo ON `other`.`foo` (id)", Action: CreateIndexAction},
+			},
+		},
+		{
+			name:   "drop index",
+			schema: "test",
+			query:  "DROP INDEX idx_foo ON foo",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "DROP INDEX idx_foo ON foo", Action: DropIndexAction},
+			},
+		},
+		{
+			name:   "drop index qualified",
+			schema: "test",
+			query:  "DROP INDEX idx_foo ON `other`.`foo`",
+			want: []*DDLEvent{
+				{Schema: "other", Table: "foo", Statement: "DROP INDEX idx_foo ON `other`.`foo`", Action: DropIndexAction},
+			},
+		},
+		{
+			name:   "alter table",
+			schema: "test",
+			query:  "ALTER TABLE foo ADD COLUMN bar INT",
+			want: []*DDLEvent{
+				{Schema: "test", Table: "foo", Statement: "ALTER TABLE foo ADD COLUMN bar INT", Action: AlterTableAction},
+			},
+		},
+		{
+			name:   "not ddl",
+			schema: "test",
+			query:  "SELECT 1",
+			want:   nil,
+		},
+	}
+
+	var p regexDDLParser
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := p.Parse(c.schema, []byte(c.query))
+			if err != nil {
+				t.Fatalf("Parse(%q): unexpected error: %v", c.query, err)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("Parse(%q): got %d events, want %d: %+v", c.query, len(got), len(c.want), got)
+			}
+			for i, e := range got {
+				w := c.want[i]
+				if *e != *w {
+					t.Fatalf("Parse(%q): event %d = %+v, want %+v", c.query, i, *e, *w)
+				}
+			}
+		})
+	}
+}
+
+func TestRegexDDLParserParseRenameInvalid(t *testing.T) {
+	var p regexDDLParser
+	_, err := p.Parse("test", []byte("RENAME TABLE foo"))
+	if err == nil {
+		t.Fatalf("Parse: expected error for malformed rename clause, got none")
+	}
+}
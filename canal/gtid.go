@@ -0,0 +1,135 @@
+package canal
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+)
+
+// handleGTIDEvent folds a MySQL GTIDEvent into the pending (not yet
+// committed) GTID. The set is only advanced to the committed position on
+// XIDEvent, mirroring how we delay saving (file, offset) until the
+// transaction boundary.
+func (c *Canal) handleGTIDEvent(e *replication.GTIDEvent) error {
+	if c.pendingGTIDSet == nil {
+		return nil
+	}
+	return c.pendingGTIDSet.Update(e.GTIDString())
+}
+
+// handleMariadbGTIDEvent folds a MariaDB GTIDEvent into the pending GTID.
+func (c *Canal) handleMariadbGTIDEvent(e *replication.MariadbGTIDEvent) error {
+	if c.pendingGTIDSet == nil {
+		return nil
+	}
+	return c.pendingGTIDSet.Update(e.GTID.String())
+}
+
+// handlePreviousGTIDsEvent seeds the pending set from the
+// PreviousGTIDsEvent written at the start of every binlog file, so that a
+// fresh StartSyncGTID picks up where the server's own bookkeeping left
+// off even if we never observed the originating transactions ourselves.
+func (c *Canal) handlePreviousGTIDsEvent(e *replication.PreviousGTIDsEvent) error {
+	if c.pendingGTIDSet == nil || c.cfg.Flavor != mysql.MySQLFlavor {
+		return nil
+	}
+	return c.pendingGTIDSet.Update(e.GTIDSets)
+}
+
+// commitGTID advances the durable GTID set to the pending one and
+// persists it through MasterInfo.Save / the MasterInfoHandler, exactly
+// like pos is advanced on XIDEvent. It hands out a clone rather than
+// c.pendingGTIDSet itself, since that pointer keeps being mutated by
+// later GTIDEvents and both MasterInfo and MasterInfoHandler implementations
+// are free to retain what they're given past this call.
+func (c *Canal) commitGTID(forceSave bool) error {
+	if c.pendingGTIDSet == nil {
+		return nil
+	}
+
+	committed := c.pendingGTIDSet.Clone()
+	c.master.UpdateGTID(committed)
+	saved, err := c.master.Save(forceSave)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if saved {
+		if h := c.getMasterInfoHandler(); h != nil {
+			if err = h.SaveGTID(committed); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// startSyncGTID is the GTID analog of startSyncBinlog's StartSync call:
+// used instead whenever a GTID set was recovered on startup, since
+// file/pos is not stable across replica promotion in GTID topologies.
+func (c *Canal) startSyncGTID(set mysql.GTIDSet) (*replication.BinlogStreamer, error) {
+	log.Infof("start sync binlog at GTID set %v", set)
+
+	c.pendingGTIDSet = set
+	s, err := c.syncer.StartSyncGTID(set)
+	if err != nil {
+		return nil, errors.Errorf("start sync replication at GTID set %v error %v", set, err)
+	}
+	return s, nil
+}
+
+// WaitUntilGTID blocks until the canal has consumed at least the given
+// GTID set, or timeout (in seconds) elapses. It is the GTID counterpart
+// of WaitUntilPos, waiting on canal's own consumption progress rather
+// than what the upstream server has written.
+func (c *Canal) WaitUntilGTID(set mysql.GTIDSet, timeout int) error {
+	if timeout <= 0 {
+		timeout = 60
+	}
+
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	for {
+		select {
+		case <-timer.C:
+			return errors.Errorf("wait GTID set %v err", set)
+		default:
+			cur := c.master.GTID()
+			if cur != nil && cur.Contain(set) {
+				return nil
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+// CatchMasterGTID waits, server-side, until the master's current executed
+// GTID set has been applied; canal's own consumption progress is not
+// consulted. It is the GTID analog of
+// CatchMasterPos. MySQL and MariaDB expose that set under different
+// variables, so the query is chosen by c.cfg.Flavor the same way
+// mysql.ParseGTIDSet is.
+func (c *Canal) CatchMasterGTID(timeout int) error {
+	var query string
+	switch c.cfg.Flavor {
+	case mysql.MariaDBFlavor:
+		query = "SELECT @@GLOBAL.gtid_current_pos"
+	default:
+		query = "SELECT @@GLOBAL.gtid_executed"
+	}
+
+	rr, err := c.Execute(query)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	gtidStr, _ := rr.GetString(0, 0)
+	set, err := mysql.ParseGTIDSet(c.cfg.Flavor, gtidStr)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	return c.waitForExecutedGTIDSet(set, timeout)
+}
@@ -0,0 +1,165 @@
+package canal
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+)
+
+// BinlogEventState carries the binlog position context around an event as
+// it passes through the handler chain for that event's type. Handlers
+// read and mutate it in place instead of returning a value, mirroring how
+// startSyncBinlog used to build up pos/forceSavePos inline.
+type BinlogEventState struct {
+	// Pos is the position the stream will be at once this event is
+	// fully processed.
+	Pos mysql.Position
+
+	// Resumable reports whether Pos is safe to persist and resume
+	// from. It is only true after a RotateEvent, an XIDEvent or a
+	// QueryEvent recognized as DDL: a mid-transaction position can't be
+	// resumed from without risking replaying or dropping part of the
+	// transaction.
+	Resumable bool
+
+	// ForceSave asks MasterInfo.Save to bypass its periodic-save
+	// throttling and persist immediately, e.g. because the schema
+	// just changed and we can't afford to lose that boundary.
+	ForceSave bool
+}
+
+// EventHandlerFunc processes one binlog event of the type it was
+// registered for. It may inspect ev.Event using a type assertion for the
+// concrete event struct that corresponds to ev.Header.EventType.
+type EventHandlerFunc func(ev *replication.BinlogEvent, state *BinlogEventState) error
+
+var rowsEventTypes = []replication.EventType{
+	replication.WRITE_ROWS_EVENTv1,
+	replication.WRITE_ROWS_EVENTv2,
+	replication.UPDATE_ROWS_EVENTv1,
+	replication.UPDATE_ROWS_EVENTv2,
+	replication.DELETE_ROWS_EVENTv1,
+	replication.DELETE_ROWS_EVENTv2,
+}
+
+// RegisterEventHandler adds h to the chain of handlers run for eventType,
+// after any handlers already registered (including the defaults this
+// package installs). This lets callers observe or augment behavior --
+// e.g. capture heartbeats, track GTIDs, watch TableMap changes, or parse
+// DDL -- without forking this file. To replace rather than chain after a
+// default handler (e.g. to change rotate/XID bookkeeping), call
+// ClearEventHandlers(eventType) first.
+func (c *Canal) RegisterEventHandler(eventType replication.EventType, h EventHandlerFunc) {
+	c.initDefaultEventHandlers()
+	c.eventHandlers[eventType] = append(c.eventHandlers[eventType], h)
+}
+
+// ClearEventHandlers removes every handler currently registered for
+// eventType, including this package's own default, so a subsequent
+// RegisterEventHandler call installs in its place instead of merely
+// running alongside it.
+func (c *Canal) ClearEventHandlers(eventType replication.EventType) {
+	c.initDefaultEventHandlers()
+	c.eventHandlers[eventType] = nil
+}
+
+// initDefaultEventHandlers installs the handlers that reproduce the
+// behavior startSyncBinlog used to hardcode, so existing users see no
+// change unless they call RegisterEventHandler themselves.
+func (c *Canal) initDefaultEventHandlers() {
+	c.eventHandlersOnce.Do(func() {
+		if c.eventHandlers == nil {
+			c.eventHandlers = make(map[replication.EventType][]EventHandlerFunc)
+		}
+		c.eventHandlers[replication.ROTATE_EVENT] = append(c.eventHandlers[replication.ROTATE_EVENT], c.onRotateEvent)
+		c.eventHandlers[replication.QUERY_EVENT] = append(c.eventHandlers[replication.QUERY_EVENT], c.onQueryEvent)
+		c.eventHandlers[replication.XID_EVENT] = append(c.eventHandlers[replication.XID_EVENT], c.onXIDEvent)
+		c.eventHandlers[replication.GTID_EVENT] = append(c.eventHandlers[replication.GTID_EVENT], c.onGTIDEvent)
+		c.eventHandlers[replication.MARIADB_GTID_EVENT] = append(c.eventHandlers[replication.MARIADB_GTID_EVENT], c.onMariadbGTIDEvent)
+		c.eventHandlers[replication.PREVIOUS_GTIDS_EVENT] = append(c.eventHandlers[replication.PREVIOUS_GTIDS_EVENT], c.onPreviousGTIDsEvent)
+		c.eventHandlers[replication.HEARTBEAT_EVENT] = append(c.eventHandlers[replication.HEARTBEAT_EVENT], c.onHeartbeatEvent)
+		for _, et := range rowsEventTypes {
+			c.eventHandlers[et] = append(c.eventHandlers[et], c.onRowsEvent)
+		}
+	})
+}
+
+// runEventHandlers dispatches ev to every handler registered for its
+// event type, in registration order, stopping at the first error.
+func (c *Canal) runEventHandlers(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	for _, h := range c.eventHandlers[ev.Header.EventType] {
+		if err := h(ev, state); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (c *Canal) onRotateEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	e := ev.Event.(*replication.RotateEvent)
+	state.Pos.Name = string(e.NextLogName)
+	state.Pos.Pos = uint32(e.Position)
+	state.Resumable = true
+	state.ForceSave = true
+	log.Infof("rotate binlog to %v", state.Pos)
+	return nil
+}
+
+func (c *Canal) onRowsEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	rowsEvent, err := c.buildRowsEvent(ev)
+	if err != nil {
+		log.Errorf("handle rows event error %v", err)
+		return errors.Trace(err)
+	}
+	if rowsEvent == nil {
+		return nil
+	}
+
+	if c.cfg.UseTransaction {
+		// buffer until the enclosing transaction's XIDEvent so it can
+		// be delivered as one atomic Transaction
+		c.appendTransactionRowsEvent(rowsEvent, ev.Header.Timestamp)
+		return nil
+	}
+	return c.travelRowsEventHandler(rowsEvent)
+}
+
+func (c *Canal) onXIDEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	if err := c.commitGTID(state.ForceSave); err != nil {
+		return errors.Trace(err)
+	}
+	if err := c.commitTransaction(state.Pos); err != nil {
+		return errors.Trace(err)
+	}
+	// try to save the position later
+	state.Resumable = true
+	return nil
+}
+
+func (c *Canal) onQueryEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	e := ev.Event.(*replication.QueryEvent)
+
+	// handle CREATE/DROP/RENAME/TRUNCATE/ALTER TABLE and CREATE/DROP INDEX
+	isDDL, err := c.handleDDLQuery(string(e.Schema), e.Query)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if isDDL {
+		state.Resumable = true
+		state.ForceSave = true
+	}
+	return nil
+}
+
+func (c *Canal) onGTIDEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	return c.handleGTIDEvent(ev.Event.(*replication.GTIDEvent))
+}
+
+func (c *Canal) onMariadbGTIDEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	return c.handleMariadbGTIDEvent(ev.Event.(*replication.MariadbGTIDEvent))
+}
+
+func (c *Canal) onPreviousGTIDsEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	return c.handlePreviousGTIDsEvent(ev.Event.(*replication.PreviousGTIDsEvent))
+}
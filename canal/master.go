@@ -0,0 +1,83 @@
+package canal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/siddontang/go-mysql/mysql"
+)
+
+// masterInfoSaveInterval throttles how often Save persists the position
+// when force isn't set, so a busy binlog doesn't turn every XIDEvent
+// into a disk write.
+const masterInfoSaveInterval = time.Second
+
+// MasterInfoHandler persists the replication position (and, in GTID
+// mode, GTID set) canal has committed, so a restart can resume instead
+// of re-scanning the binlog from the start.
+type MasterInfoHandler interface {
+	SavePos(name string, pos uint32) error
+	SaveGTID(set mysql.GTIDSet) error
+}
+
+// MasterInfo tracks the (file, offset), and in GTID mode the GTID set,
+// canal has committed so far.
+type MasterInfo struct {
+	mu sync.Mutex
+
+	Name     string
+	Position uint32
+
+	gtidSet    mysql.GTIDSet
+	lastSaveAt time.Time
+}
+
+// Update records the position canal has committed up to.
+func (m *MasterInfo) Update(name string, pos uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Name = name
+	m.Position = pos
+}
+
+// Pos returns the last position recorded via Update.
+func (m *MasterInfo) Pos() mysql.Position {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return mysql.Position{Name: m.Name, Pos: m.Position}
+}
+
+// GTID returns a clone of the GTID set last recorded via UpdateGTID, or
+// nil when canal isn't running in GTID mode. It clones rather than
+// handing out m.gtidSet itself, since callers (e.g. startSyncGTID,
+// seeding pendingGTIDSet) otherwise end up sharing the same pointer
+// that later GTIDEvents mutate in place -- before those events' own
+// transactions have actually committed.
+func (m *MasterInfo) GTID() mysql.GTIDSet {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.gtidSet == nil {
+		return nil
+	}
+	return m.gtidSet.Clone()
+}
+
+// UpdateGTID records the GTID set canal has committed up to.
+func (m *MasterInfo) UpdateGTID(set mysql.GTIDSet) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gtidSet = set
+}
+
+// Save reports whether the current position is due to be persisted:
+// always when force is set, otherwise throttled to masterInfoSaveInterval.
+func (m *MasterInfo) Save(force bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !force && time.Since(m.lastSaveAt) < masterInfoSaveInterval {
+		return false, nil
+	}
+	m.lastSaveAt = time.Now()
+	return true, nil
+}
@@ -0,0 +1,26 @@
+package canal
+
+import (
+	"testing"
+
+	"github.com/siddontang/go-mysql/mysql"
+)
+
+func TestMasterInfoGTIDReturnsIsolatedCopy(t *testing.T) {
+	set, err := mysql.ParseGTIDSet(mysql.MySQLFlavor, "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var m MasterInfo
+	m.UpdateGTID(set)
+
+	got := m.GTID()
+	if err := set.Update("3E11FA47-71CA-11E1-9E33-C80AA9429562:6-20"); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	if want, gotStr := "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5", got.String(); gotStr != want {
+		t.Fatalf("GTID() mutated by later Update on the set passed to UpdateGTID: String() = %q, want %q", gotStr, want)
+	}
+}
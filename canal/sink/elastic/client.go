@@ -0,0 +1,152 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// bulkAction is one line-pair of the Elasticsearch _bulk request body:
+// an action/metadata object followed by an optional document body.
+type bulkAction struct {
+	meta map[string]interface{}
+	doc  map[string]interface{}
+}
+
+// client is a minimal Elasticsearch HTTP client: just enough _bulk
+// support to drive this sink, with retry on the errors that are worth
+// retrying (429 back-pressure, 5xx).
+type client struct {
+	addr     string
+	user     string
+	password string
+	http     *http.Client
+
+	maxRetries int
+	retryWait  time.Duration
+}
+
+func newClient(cfg *Config) *client {
+	return &client{
+		addr:       cfg.Addr,
+		user:       cfg.User,
+		password:   cfg.Password,
+		http:       &http.Client{Timeout: 30 * time.Second},
+		maxRetries: 5,
+		retryWait:  200 * time.Millisecond,
+	}
+}
+
+// bulk sends actions to _bulk, retrying the whole batch with exponential
+// backoff on 429/5xx responses or transport errors.
+func (c *client) bulk(actions []bulkAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	body, err := encodeBulkBody(actions)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	wait := c.retryWait
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			log.Warnf("elastic: bulk request failed (%v), retrying in %v (attempt %d/%d)", lastErr, wait, attempt, c.maxRetries)
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		retryable, err := c.doBulk(body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Annotatef(lastErr, "elastic: bulk request failed after %d retries", c.maxRetries)
+}
+
+// doBulk issues one _bulk HTTP request. The bool return reports whether
+// the error (if any) is worth retrying.
+func (c *client) doBulk(body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", c.addr+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if len(c.user) > 0 {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// a transport-level error (timeout, connection refused, ...)
+		// is always worth a retry
+		return true, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return true, errors.Errorf("elastic: bulk http %d: %s", resp.StatusCode, respBody)
+	}
+	if resp.StatusCode >= 400 {
+		return false, errors.Errorf("elastic: bulk http %d: %s", resp.StatusCode, respBody)
+	}
+
+	return false, checkBulkItemErrors(respBody)
+}
+
+// checkBulkItemErrors reports the first per-item failure in a bulk
+// response, if any. Per-item failures aren't retried here: a malformed
+// document will fail identically next time, so retrying would just loop.
+func checkBulkItemErrors(respBody []byte) error {
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return errors.Trace(err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	for _, item := range result.Items {
+		for action, info := range item {
+			if info.Status >= 300 {
+				return errors.Errorf("elastic: bulk item %s failed with status %d: %s", action, info.Status, info.Error)
+			}
+		}
+	}
+	return errors.Errorf("elastic: bulk response reported errors")
+}
+
+func encodeBulkBody(actions []bulkAction) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, a := range actions {
+		if err := enc.Encode(a.meta); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if a.doc != nil {
+			if err := enc.Encode(a.doc); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
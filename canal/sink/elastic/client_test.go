@@ -0,0 +1,63 @@
+package elastic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeBulkBodyMetaThenOptionalDoc(t *testing.T) {
+	actions := []bulkAction{
+		{
+			meta: map[string]interface{}{"index": map[string]interface{}{"_index": "users", "_id": "1"}},
+			doc:  map[string]interface{}{"name": "alice"},
+		},
+		{
+			meta: map[string]interface{}{"delete": map[string]interface{}{"_index": "users", "_id": "2"}},
+		},
+	}
+
+	body, err := encodeBulkBody(actions)
+	if err != nil {
+		t.Fatalf("encodeBulkBody: unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(body), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("encodeBulkBody: got %d lines, want 3 (index meta, doc, delete meta): %q", len(lines), body)
+	}
+	if !strings.Contains(lines[0], `"index"`) || !strings.Contains(lines[1], `"alice"`) || !strings.Contains(lines[2], `"delete"`) {
+		t.Fatalf("encodeBulkBody: unexpected line contents: %q", lines)
+	}
+}
+
+func TestCheckBulkItemErrorsNoErrors(t *testing.T) {
+	if err := checkBulkItemErrors([]byte(`{"errors":false,"items":[]}`)); err != nil {
+		t.Fatalf("checkBulkItemErrors: unexpected error: %v", err)
+	}
+}
+
+func TestCheckBulkItemErrorsReportsFirstFailure(t *testing.T) {
+	body := `{
+		"errors": true,
+		"items": [
+			{"index": {"status": 200}},
+			{"index": {"status": 409, "error": {"type": "version_conflict_engine_exception"}}}
+		]
+	}`
+	err := checkBulkItemErrors([]byte(body))
+	if err == nil {
+		t.Fatalf("checkBulkItemErrors: expected error, got none")
+	}
+	if !strings.Contains(err.Error(), "409") {
+		t.Fatalf("checkBulkItemErrors: error %q does not mention the failing status", err)
+	}
+}
+
+func TestCheckBulkItemErrorsFlagSetWithoutIdentifiableItem(t *testing.T) {
+	// errors:true but no item actually reports status >= 300 -- still an
+	// error, just without a specific item to blame.
+	body := `{"errors": true, "items": [{"index": {"status": 200}}]}`
+	if err := checkBulkItemErrors([]byte(body)); err == nil {
+		t.Fatalf("checkBulkItemErrors: expected error when errors flag is set, got none")
+	}
+}
@@ -0,0 +1,57 @@
+package elastic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/siddontang/go-mysql/canal"
+)
+
+// TestSinkFlushSafeConcurrentWithDo exercises Sink's mutex: Do (which may
+// trigger its own threshold flush) and an external Flush -- the shape a
+// caller-owned staleness ticker would add on top of Do's batch-size
+// trigger -- run concurrently against the same buffer/pending fields.
+func TestSinkFlushSafeConcurrentWithDo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(&Config{
+		Addr: srv.URL,
+		Rules: []*Rule{
+			{Schema: "test", Table: "users", ID: []string{"id"}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewSink: unexpected error: %v", err)
+	}
+	sink.SetBatchLimits(2, defaultBatchBytes)
+
+	tbl := newTestTable()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			e := &canal.RowsEvent{Table: tbl, Action: canal.InsertAction, Rows: [][]interface{}{{i, 1, "a", "x"}}}
+			if err := sink.Do(e); err != nil {
+				t.Errorf("Do: unexpected error: %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := sink.Flush(); err != nil {
+				t.Errorf("Flush: unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("final Flush: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package elastic
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/juju/errors"
+)
+
+// Rule describes how rows from one MySQL table are mapped onto
+// Elasticsearch documents.
+type Rule struct {
+	Schema string `toml:"schema"`
+	Table  string `toml:"table"`
+
+	// Index is the target Elasticsearch index. Defaults to Table.
+	Index string `toml:"index"`
+
+	// ID names the column(s) that make up the document id. A single
+	// column is used as-is; more than one is joined with "_".
+	ID []string `toml:"id"`
+
+	// FieldMapping renames a column to a different document field.
+	// Columns not listed keep their MySQL name.
+	FieldMapping map[string]string `toml:"field"`
+
+	// ExcludeColumns lists columns that should never be sent to
+	// Elasticsearch, e.g. internal bookkeeping columns.
+	ExcludeColumns []string `toml:"exclude"`
+
+	// Parent, if set, names the column holding the parent document id
+	// for index types using the parent/child join.
+	Parent string `toml:"parent"`
+
+	excludeSet map[string]struct{}
+}
+
+// Config is the top level rules file: one [[rule]] table per MySQL
+// table that should be mirrored into Elasticsearch.
+type Config struct {
+	Addr     string  `toml:"addr"`
+	User     string  `toml:"user"`
+	Password string  `toml:"password"`
+	Rules    []*Rule `toml:"rule"`
+}
+
+// LoadConfig reads and validates a rules file like:
+//
+//	addr = "http://127.0.0.1:9200"
+//
+//	[[rule]]
+//	schema = "test"
+//	table = "user"
+//	index = "users"
+//	id = ["id"]
+//	[rule.field]
+//	user_name = "name"
+func LoadConfig(path string) (*Config, error) {
+	var cfg Config
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	if len(cfg.Addr) == 0 {
+		return nil, errors.New("elastic: addr is required")
+	}
+
+	for _, r := range cfg.Rules {
+		if err := r.prepare(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return &cfg, nil
+}
+
+func (r *Rule) prepare() error {
+	if len(r.Schema) == 0 || len(r.Table) == 0 {
+		return errors.Errorf("rule must set schema and table")
+	}
+	if len(r.Index) == 0 {
+		r.Index = r.Table
+	}
+	if len(r.ID) == 0 {
+		return errors.Errorf("rule %s.%s must set id", r.Schema, r.Table)
+	}
+
+	r.excludeSet = make(map[string]struct{}, len(r.ExcludeColumns))
+	for _, c := range r.ExcludeColumns {
+		r.excludeSet[c] = struct{}{}
+	}
+	return nil
+}
+
+func (r *Rule) excluded(column string) bool {
+	_, ok := r.excludeSet[column]
+	return ok
+}
+
+func (r *Rule) fieldName(column string) string {
+	if name, ok := r.FieldMapping[column]; ok {
+		return name
+	}
+	return column
+}
+
+func ruleKey(schema, table string) string {
+	return schema + "." + table
+}
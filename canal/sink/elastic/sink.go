@@ -0,0 +1,262 @@
+// Package elastic implements a canal.RowsEventHandler that mirrors MySQL
+// rows into Elasticsearch, driven by a declarative per-table rules file.
+// It is meant to make go-mysql usable out of the box as a MySQL ->
+// Elasticsearch CDC bridge, the most common use case built on top of
+// canal, without every user reinventing the row->document converter.
+package elastic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/siddontang/go-mysql/canal"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/schema"
+)
+
+const (
+	defaultBatchSize  = 1000
+	defaultBatchBytes = 5 * 1024 * 1024
+)
+
+// Sink is a canal.RowsEventHandler that batches row changes into
+// Elasticsearch _bulk requests. The binlog position is only handed to
+// underlying (the real MasterInfoHandler) once a batch has been
+// acknowledged by Elasticsearch, so a crash mid-batch replays from the
+// last durable ES state rather than skipping rows.
+//
+// Flush only fires automatically from Do once a batch threshold is
+// reached; callers that also want to bound staleness on a low-traffic
+// table are expected to call Flush from their own ticker. mu makes that
+// safe to run concurrently with the replication goroutine's Do/SavePos/
+// SaveGTID calls.
+type Sink struct {
+	client *client
+	rules  map[string]*Rule
+
+	underlying canal.MasterInfoHandler
+
+	batchSize  int
+	batchBytes int
+
+	mu sync.Mutex
+
+	buffer      []bulkAction
+	bufferBytes int
+
+	pendingName string
+	pendingPos  uint32
+	pendingGTID mysql.GTIDSet
+}
+
+// NewSink builds a Sink from a rules file. underlying, if non-nil, is
+// the canal.MasterInfoHandler whose SavePos/SaveGTID this Sink will
+// delegate to after each successful bulk flush; pass nil to let the
+// Sink only push to Elasticsearch without persisting position itself.
+func NewSink(cfg *Config, underlying canal.MasterInfoHandler) (*Sink, error) {
+	rules := make(map[string]*Rule, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		if err := r.prepare(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		rules[ruleKey(r.Schema, r.Table)] = r
+	}
+
+	return &Sink{
+		client:     newClient(cfg),
+		rules:      rules,
+		underlying: underlying,
+		batchSize:  defaultBatchSize,
+		batchBytes: defaultBatchBytes,
+	}, nil
+}
+
+// SetBatchLimits overrides the default bulk size/byte thresholds.
+func (s *Sink) SetBatchLimits(size, bytes int) {
+	s.batchSize = size
+	s.batchBytes = bytes
+}
+
+func (s *Sink) String() string {
+	return "ElasticSink"
+}
+
+// Do implements canal.RowsEventHandler, converting e into bulk actions
+// for its rule (tables with no rule are silently ignored) and flushing
+// once the batch thresholds are hit.
+func (s *Sink) Do(e *canal.RowsEvent) error {
+	rule, ok := s.rules[ruleKey(e.Table.Schema, e.Table.Name)]
+	if !ok {
+		return nil
+	}
+
+	actions, err := buildBulkActions(rule, e)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffer = append(s.buffer, actions...)
+	s.bufferBytes += actionsApproxSize(actions)
+
+	if len(s.buffer) >= s.batchSize || s.bufferBytes >= s.batchBytes {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+// SavePos implements canal.MasterInfoHandler: the position is only
+// remembered, not persisted, until the next successful Flush.
+func (s *Sink) SavePos(name string, pos uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingName = name
+	s.pendingPos = pos
+	return nil
+}
+
+// SaveGTID implements the GTID extension of canal.MasterInfoHandler, for
+// the same deferred-persistence reason as SavePos.
+func (s *Sink) SaveGTID(set mysql.GTIDSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingGTID = set
+	return nil
+}
+
+// Flush sends any buffered actions to Elasticsearch and, once
+// acknowledged, forwards the position pending since the last flush to
+// the underlying MasterInfoHandler. It's safe to call concurrently with
+// Do/SavePos/SaveGTID -- e.g. from a caller-owned ticker that wants to
+// bound how long a low-traffic table's rows can sit unflushed, on top of
+// Do's own batch-threshold triggered flushes.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// flushLocked is Flush's implementation; callers must hold s.mu.
+func (s *Sink) flushLocked() error {
+	if len(s.buffer) > 0 {
+		if err := s.client.bulk(s.buffer); err != nil {
+			// leave buffer and pending position untouched so a retry (or
+			// process restart against the underlying's last saved pos)
+			// picks up exactly these rows again
+			return errors.Trace(err)
+		}
+
+		s.buffer = s.buffer[:0]
+		s.bufferBytes = 0
+	}
+
+	if s.underlying == nil || len(s.pendingName) == 0 {
+		return nil
+	}
+	if err := s.underlying.SavePos(s.pendingName, s.pendingPos); err != nil {
+		return errors.Trace(err)
+	}
+	if s.pendingGTID != nil {
+		if err := s.underlying.SaveGTID(s.pendingGTID); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func buildBulkActions(rule *Rule, e *canal.RowsEvent) ([]bulkAction, error) {
+	switch e.Action {
+	case canal.InsertAction:
+		return rowsToActions(rule, e, e.Rows, indexAction)
+	case canal.DeleteAction:
+		return rowsToActions(rule, e, e.Rows, deleteAction)
+	case canal.UpdateAction:
+		// update rows come as (before, after) pairs; only the after
+		// image is relevant to the target document
+		afters := make([][]interface{}, 0, len(e.Rows)/2)
+		for i := 1; i < len(e.Rows); i += 2 {
+			afters = append(afters, e.Rows[i])
+		}
+		return rowsToActions(rule, e, afters, indexAction)
+	default:
+		return nil, errors.Errorf("elastic: unsupported action %s", e.Action)
+	}
+}
+
+func rowsToActions(rule *Rule, e *canal.RowsEvent, rows [][]interface{}, kind string) ([]bulkAction, error) {
+	actions := make([]bulkAction, 0, len(rows))
+	for _, row := range rows {
+		id, err := buildDocID(rule, e.Table, row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		meta := map[string]interface{}{
+			kind: map[string]interface{}{
+				"_index": rule.Index,
+				"_id":    id,
+			},
+		}
+
+		var doc map[string]interface{}
+		if kind == indexAction {
+			doc = buildDoc(rule, e.Table, row)
+		}
+		actions = append(actions, bulkAction{meta: meta, doc: doc})
+	}
+	return actions, nil
+}
+
+const (
+	indexAction  = "index"
+	deleteAction = "delete"
+)
+
+func buildDocID(rule *Rule, t *schema.Table, row []interface{}) (string, error) {
+	parts := make([]string, 0, len(rule.ID))
+	for _, col := range rule.ID {
+		idx := columnIndex(t, col)
+		if idx < 0 {
+			return "", errors.Errorf("elastic: id column %s not found in %s.%s", col, t.Schema, t.Name)
+		}
+		parts = append(parts, fmt.Sprintf("%v", row[idx]))
+	}
+	return strings.Join(parts, "_"), nil
+}
+
+func buildDoc(rule *Rule, t *schema.Table, row []interface{}) map[string]interface{} {
+	doc := make(map[string]interface{}, len(t.Columns))
+	for i, col := range t.Columns {
+		if i >= len(row) || rule.excluded(col.Name) {
+			continue
+		}
+		doc[rule.fieldName(col.Name)] = row[i]
+	}
+	if len(rule.Parent) > 0 {
+		if idx := columnIndex(t, rule.Parent); idx >= 0 {
+			doc["_parent"] = row[idx]
+		}
+	}
+	return doc
+}
+
+func columnIndex(t *schema.Table, name string) int {
+	for i, col := range t.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func actionsApproxSize(actions []bulkAction) int {
+	size := 0
+	for _, a := range actions {
+		size += len(fmt.Sprint(a.meta)) + len(fmt.Sprint(a.doc))
+	}
+	return size
+}
@@ -0,0 +1,181 @@
+package elastic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/siddontang/go-mysql/canal"
+	"github.com/siddontang/go-mysql/schema"
+)
+
+func newTestTable() *schema.Table {
+	return &schema.Table{
+		Schema: "test",
+		Name:   "users",
+		Columns: []schema.TableColumn{
+			{Name: "id"},
+			{Name: "org_id"},
+			{Name: "name"},
+			{Name: "secret"},
+		},
+	}
+}
+
+func newTestRule(t *testing.T, id []string, fieldMapping map[string]string, exclude []string) *Rule {
+	t.Helper()
+	r := &Rule{
+		Schema:         "test",
+		Table:          "users",
+		Index:          "users",
+		ID:             id,
+		FieldMapping:   fieldMapping,
+		ExcludeColumns: exclude,
+	}
+	if err := r.prepare(); err != nil {
+		t.Fatalf("prepare: unexpected error: %v", err)
+	}
+	return r
+}
+
+func TestBuildDocIDSingleAndCompositeColumns(t *testing.T) {
+	tbl := newTestTable()
+	row := []interface{}{42, 7, "alice", "shh"}
+
+	cases := []struct {
+		name string
+		id   []string
+		want string
+	}{
+		{name: "single column", id: []string{"id"}, want: "42"},
+		{name: "composite columns joined with underscore", id: []string{"org_id", "id"}, want: "7_42"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rule := newTestRule(t, c.id, nil, nil)
+			got, err := buildDocID(rule, tbl, row)
+			if err != nil {
+				t.Fatalf("buildDocID: unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("buildDocID() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestBuildDocIDMissingColumn(t *testing.T) {
+	tbl := newTestTable()
+	rule := newTestRule(t, []string{"no_such_column"}, nil, nil)
+
+	if _, err := buildDocID(rule, tbl, []interface{}{1, 2, "a", "b"}); err == nil {
+		t.Fatalf("buildDocID: expected error for unknown id column, got none")
+	}
+}
+
+func TestBuildDocAppliesFieldMappingAndExclusion(t *testing.T) {
+	tbl := newTestTable()
+	rule := newTestRule(t, []string{"id"}, map[string]string{"name": "full_name"}, []string{"secret"})
+	row := []interface{}{42, 7, "alice", "shh"}
+
+	got := buildDoc(rule, tbl, row)
+	want := map[string]interface{}{
+		"id":        42,
+		"org_id":    7,
+		"full_name": "alice",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildDoc() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildDocParentColumn(t *testing.T) {
+	tbl := newTestTable()
+	rule := newTestRule(t, []string{"id"}, nil, []string{"secret"})
+	rule.Parent = "org_id"
+	row := []interface{}{42, 7, "alice", "shh"}
+
+	got := buildDoc(rule, tbl, row)
+	if got["_parent"] != 7 {
+		t.Fatalf("buildDoc()[\"_parent\"] = %v, want 7", got["_parent"])
+	}
+}
+
+func TestBuildBulkActionsInsertAndDelete(t *testing.T) {
+	tbl := newTestTable()
+	rule := newTestRule(t, []string{"id"}, nil, nil)
+
+	e := &canal.RowsEvent{
+		Table:  tbl,
+		Action: canal.InsertAction,
+		Rows:   [][]interface{}{{1, 1, "a", "x"}, {2, 1, "b", "y"}},
+	}
+	actions, err := buildBulkActions(rule, e)
+	if err != nil {
+		t.Fatalf("buildBulkActions: unexpected error: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("buildBulkActions: got %d actions, want 2", len(actions))
+	}
+	for i, a := range actions {
+		if a.doc == nil {
+			t.Fatalf("action %d: insert should carry a document body", i)
+		}
+		meta, ok := a.meta[indexAction].(map[string]interface{})
+		if !ok {
+			t.Fatalf("action %d: expected %q action metadata, got %+v", i, indexAction, a.meta)
+		}
+		if meta["_id"] == "" {
+			t.Fatalf("action %d: empty _id", i)
+		}
+	}
+
+	e.Action = canal.DeleteAction
+	actions, err = buildBulkActions(rule, e)
+	if err != nil {
+		t.Fatalf("buildBulkActions: unexpected error: %v", err)
+	}
+	for i, a := range actions {
+		if a.doc != nil {
+			t.Fatalf("action %d: delete should not carry a document body, got %+v", i, a.doc)
+		}
+		if _, ok := a.meta[deleteAction]; !ok {
+			t.Fatalf("action %d: expected %q action metadata, got %+v", i, deleteAction, a.meta)
+		}
+	}
+}
+
+func TestBuildBulkActionsUpdateUsesAfterImageOnly(t *testing.T) {
+	tbl := newTestTable()
+	rule := newTestRule(t, []string{"id"}, nil, nil)
+
+	// update rows arrive as (before, after) pairs.
+	e := &canal.RowsEvent{
+		Table:  tbl,
+		Action: canal.UpdateAction,
+		Rows: [][]interface{}{
+			{1, 1, "old-name", "x"}, {1, 1, "new-name", "x"},
+		},
+	}
+
+	actions, err := buildBulkActions(rule, e)
+	if err != nil {
+		t.Fatalf("buildBulkActions: unexpected error: %v", err)
+	}
+	if len(actions) != 1 {
+		t.Fatalf("buildBulkActions(update): got %d actions, want 1", len(actions))
+	}
+	if got := actions[0].doc["name"]; got != "new-name" {
+		t.Fatalf("buildBulkActions(update): doc[name] = %v, want after-image %q", got, "new-name")
+	}
+}
+
+func TestBuildBulkActionsUnsupportedAction(t *testing.T) {
+	tbl := newTestTable()
+	rule := newTestRule(t, []string{"id"}, nil, nil)
+
+	e := &canal.RowsEvent{Table: tbl, Action: "truncate", Rows: [][]interface{}{{1, 1, "a", "x"}}}
+	if _, err := buildBulkActions(rule, e); err == nil {
+		t.Fatalf("buildBulkActions: expected error for unsupported action, got none")
+	}
+}
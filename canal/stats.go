@@ -0,0 +1,187 @@
+package canal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+)
+
+// stalledMultiplier is how many heartbeat periods may pass with no event
+// at all before the master is considered stalled.
+const stalledMultiplier = 3
+
+// Stats is a snapshot of Canal's consumption progress and health,
+// returned by Canal.Stats(). It's meant for exposing on an operator's
+// own /debug or metrics endpoint.
+type Stats struct {
+	// Lag is how far behind the master's wall clock the last consumed
+	// event was, computed from that event's header timestamp.
+	Lag time.Duration
+
+	// SinceLastEvent is how long ago we last received any event
+	// (including heartbeats) from the master.
+	SinceLastEvent time.Duration
+
+	LastPos  mysql.Position
+	LastGTID mysql.GTIDSet
+
+	// EventsByType counts every event seen so far, by wire event type.
+	EventsByType map[replication.EventType]uint64
+
+	BytesConsumed uint64
+}
+
+// MetricsReporter lets callers wire Canal's event stream into their own
+// metrics system (Prometheus, statsd, ...) instead of only polling
+// Stats().
+type MetricsReporter interface {
+	ReportLag(lag time.Duration)
+	ReportEvent(eventType replication.EventType, bytes uint64)
+}
+
+// SetMetricsReporter installs r to be called for every binlog event
+// Canal consumes, in addition to the counters available via Stats().
+func (c *Canal) SetMetricsReporter(r MetricsReporter) {
+	c.metricsReporter = r
+}
+
+type statsState struct {
+	mu sync.Mutex
+
+	eventsByType  map[replication.EventType]uint64
+	bytesConsumed uint64
+
+	lag           time.Duration
+	lastEventAt   time.Time
+	lastHeartbeat time.Time
+	lastPos       mysql.Position
+	lastGTID      mysql.GTIDSet
+}
+
+// recordEvent updates stats bookkeeping for one consumed event and feeds
+// the MetricsReporter, if any. It's called for every event, including
+// ones no EventHandlerFunc is registered for.
+func (c *Canal) recordEvent(ev *replication.BinlogEvent, pos mysql.Position) {
+	now := time.Now()
+	size := uint64(ev.Header.EventSize)
+
+	// HEARTBEAT_EVENT carries a zero header timestamp on the wire, so it
+	// doesn't represent any real point in the master's event stream; skip
+	// it here rather than letting it clobber Lag with a ~56-year reading.
+	isHeartbeat := ev.Header.EventType == replication.HEARTBEAT_EVENT
+	var lag time.Duration
+	if !isHeartbeat {
+		lag = now.Sub(time.Unix(int64(ev.Header.Timestamp), 0))
+	}
+
+	c.stats.mu.Lock()
+	if c.stats.eventsByType == nil {
+		c.stats.eventsByType = make(map[replication.EventType]uint64)
+	}
+	c.stats.eventsByType[ev.Header.EventType]++
+	c.stats.bytesConsumed += size
+	if !isHeartbeat {
+		c.stats.lag = lag
+	}
+	c.stats.lastEventAt = now
+	c.stats.lastPos = pos
+	if isHeartbeat {
+		c.stats.lastHeartbeat = now
+	}
+	if set := c.pendingGTIDSet; set != nil {
+		// Clone: pendingGTIDSet keeps mutating as later events are
+		// folded in, but Stats() hands lastGTID out to callers that
+		// may hold onto it.
+		c.stats.lastGTID = set.Clone()
+	}
+	c.stats.mu.Unlock()
+
+	if c.metricsReporter != nil {
+		if !isHeartbeat {
+			c.metricsReporter.ReportLag(lag)
+		}
+		c.metricsReporter.ReportEvent(ev.Header.EventType, size)
+	}
+}
+
+// Stats returns a snapshot of Canal's current replication lag and
+// consumption counters.
+func (c *Canal) Stats() Stats {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	byType := make(map[replication.EventType]uint64, len(c.stats.eventsByType))
+	for et, n := range c.stats.eventsByType {
+		byType[et] = n
+	}
+
+	var sinceLastEvent time.Duration
+	if !c.stats.lastEventAt.IsZero() {
+		sinceLastEvent = time.Since(c.stats.lastEventAt)
+	}
+
+	return Stats{
+		Lag:            c.stats.lag,
+		SinceLastEvent: sinceLastEvent,
+		LastPos:        c.stats.lastPos,
+		LastGTID:       c.stats.lastGTID,
+		EventsByType:   byType,
+		BytesConsumed:  c.stats.bytesConsumed,
+	}
+}
+
+// Notify returns a channel on which Canal reports asynchronous problems
+// it doesn't treat as fatal, such as a stalled master -- so operators can
+// alert on them without having to poll Stats(). The channel is buffered;
+// a notification that can't be delivered immediately is dropped rather
+// than blocking replication.
+func (c *Canal) Notify() <-chan error {
+	c.notifyOnce.Do(func() {
+		c.notifyCh = make(chan error, 16)
+	})
+	return c.notifyCh
+}
+
+func (c *Canal) notify(err error) {
+	c.notifyOnce.Do(func() {
+		c.notifyCh = make(chan error, 16)
+	})
+	select {
+	case c.notifyCh <- err:
+	default:
+	}
+}
+
+// checkMasterStalled reports a stalled-master error if no event
+// (including heartbeats) has arrived for stalledMultiplier heartbeat
+// periods, replacing the old approach of just doubling the GetEvent
+// read timeout forever and hoping something eventually arrived.
+func (c *Canal) checkMasterStalled() error {
+	period := c.cfg.HeartbeatPeriod
+	if period <= 0 {
+		return nil
+	}
+
+	c.stats.mu.Lock()
+	lastEventAt := c.stats.lastEventAt
+	c.stats.mu.Unlock()
+
+	if lastEventAt.IsZero() {
+		return nil
+	}
+
+	since := time.Since(lastEventAt)
+	if since > period*stalledMultiplier {
+		return errors.Errorf("replication master stalled: no event received for %v (heartbeat period %v)", since, period)
+	}
+	return nil
+}
+
+func (c *Canal) onHeartbeatEvent(ev *replication.BinlogEvent, state *BinlogEventState) error {
+	// nothing to do beyond the bookkeeping recordEvent already does;
+	// a heartbeat carries no position of its own to advance to
+	return nil
+}
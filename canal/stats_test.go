@@ -0,0 +1,40 @@
+package canal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+)
+
+func TestRecordEventHeartbeatDoesNotClobberLag(t *testing.T) {
+	var c Canal
+
+	// A normal data event a while back establishes a real, non-zero lag.
+	c.recordEvent(&replication.BinlogEvent{
+		Header: &replication.EventHeader{
+			EventType: replication.WRITE_ROWS_EVENTv2,
+			Timestamp: uint32(time.Now().Add(-5 * time.Second).Unix()),
+			EventSize: 10,
+		},
+	}, mysql.Position{Name: "mysql-bin.000001", Pos: 100})
+	wantLag := c.stats.lag
+
+	// HEARTBEAT_EVENT carries a zero header timestamp on the wire; it
+	// must not be treated as a real event for lag purposes.
+	c.recordEvent(&replication.BinlogEvent{
+		Header: &replication.EventHeader{
+			EventType: replication.HEARTBEAT_EVENT,
+			Timestamp: 0,
+			EventSize: 10,
+		},
+	}, mysql.Position{Name: "mysql-bin.000001", Pos: 100})
+
+	if c.stats.lag != wantLag {
+		t.Fatalf("recordEvent(HEARTBEAT_EVENT): lag = %v, want unchanged %v", c.stats.lag, wantLag)
+	}
+	if c.stats.lastHeartbeat.IsZero() {
+		t.Fatalf("recordEvent(HEARTBEAT_EVENT): lastHeartbeat was not updated")
+	}
+}
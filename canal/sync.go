@@ -1,7 +1,9 @@
 package canal
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,23 +25,39 @@ var (
 func (c *Canal) startSyncBinlog() error {
 	pos := mysql.Position{c.master.Name, c.master.Position}
 
-	log.Infof("start sync binlog at %v", pos)
-
-	s, err := c.syncer.StartSync(pos)
+	var s *replication.BinlogStreamer
+	var err error
+	if gset := c.master.GTID(); gset != nil {
+		// GTID topologies may have failed over since we last saved
+		// pos, so file/pos can no longer be trusted; resume from the
+		// GTID set instead.
+		s, err = c.startSyncGTID(gset)
+	} else {
+		log.Infof("start sync binlog at %v", pos)
+		s, err = c.syncer.StartSync(pos)
+		if err != nil {
+			err = errors.Errorf("start sync replication at %v error %v", pos, err)
+		}
+	}
 	if err != nil {
-		return errors.Errorf("start sync replication at %v error %v", pos, err)
+		return errors.Trace(err)
 	}
 
-	timeout := time.Second
-	forceSavePos := false
-	posSaved := false
+	c.initDefaultEventHandlers()
+
 	for {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		ev, err := s.GetEvent(ctx)
 		cancel()
 
 		if err == context.DeadlineExceeded {
-			timeout = 2 * timeout
+			// no event within the read timeout isn't itself a
+			// problem, but if it's been too long since *any* event
+			// (data or heartbeat), the master may be down or
+			// unreachable -- surface that instead of looping forever.
+			if stalled := c.checkMasterStalled(); stalled != nil {
+				c.notify(stalled)
+			}
 			continue
 		}
 
@@ -47,52 +65,25 @@ func (c *Canal) startSyncBinlog() error {
 			return errors.Trace(err)
 		}
 
-		timeout = time.Second
-
 		//next binlog pos
 		pos.Pos = ev.Header.LogPos
-
-		forceSavePos = false
+		c.recordEvent(ev, pos)
 
 		// We only save position with RotateEvent and XIDEvent.
 		// For RowsEvent, we can't save the position until meeting XIDEvent
 		// which tells the whole transaction is over.
-		// TODO: If we meet any DDL query, we must save too.
-		switch e := ev.Event.(type) {
-		case *replication.RotateEvent:
-			pos.Name = string(e.NextLogName)
-			pos.Pos = uint32(e.Position)
-			// r.ev <- pos
-			forceSavePos = true
-			log.Infof("rotate binlog to %v", pos)
-		case *replication.RowsEvent:
-			// we only focus row based event
-			if err = c.handleRowsEvent(ev); err != nil {
-				log.Errorf("handle rows event error %v", err)
-				return errors.Trace(err)
-			}
-			continue
-		case *replication.XIDEvent:
-			// try to save the position later
-		case *replication.QueryEvent:
-			// handle alert table query
-			if mb := expAlterTable.FindSubmatch(e.Query); mb != nil {
-				if len(mb[1]) == 0 {
-					mb[1] = e.Schema
-				}
-				c.ClearTableCache(mb[1], mb[2])
-				log.Infof("table structure changed, clear table cache: %s.%s\n", mb[1], mb[2])
-				forceSavePos = true
-			} else {
-				// skip others
-				continue
-			}
-		default:
+		state := &BinlogEventState{Pos: pos}
+		if err = c.runEventHandlers(ev, state); err != nil {
+			return errors.Trace(err)
+		}
+
+		pos = state.Pos
+		if !state.Resumable {
 			continue
 		}
 
 		c.master.Update(pos.Name, pos.Pos)
-		posSaved, err = c.master.Save(forceSavePos)
+		posSaved, err := c.master.Save(state.ForceSave)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -131,7 +122,10 @@ func (c *Canal) isSkipedSchema(schema string) bool {
 	return false
 }
 
-func (c *Canal) handleRowsEvent(e *replication.BinlogEvent) error {
+// buildRowsEvent turns a raw RowsEvent into our own *RowsEvent, resolving
+// the table and action it represents. It returns (nil, nil) when the
+// event's schema is configured to be skipped.
+func (c *Canal) buildRowsEvent(e *replication.BinlogEvent) (*RowsEvent, error) {
 	ev := e.Event.(*replication.RowsEvent)
 
 	// Caveat: table may be altered at runtime.
@@ -139,12 +133,12 @@ func (c *Canal) handleRowsEvent(e *replication.BinlogEvent) error {
 	table := string(ev.Table.Table)
 
 	if c.isSkipedSchema(schema) {
-		return nil
+		return nil, nil
 	}
 
 	t, err := c.GetTable(schema, table)
 	if err != nil {
-		return errors.Trace(err)
+		return nil, errors.Trace(err)
 	}
 	var action string
 	switch e.Header.EventType {
@@ -155,12 +149,17 @@ func (c *Canal) handleRowsEvent(e *replication.BinlogEvent) error {
 	case replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
 		action = UpdateAction
 	default:
-		return errors.Errorf("%s not supported now", e.Header.EventType)
+		return nil, errors.Errorf("%s not supported now", e.Header.EventType)
 	}
-	events := newRowsEvent(t, action, ev.Rows)
-	return c.travelRowsEventHandler(events)
+	return newRowsEvent(t, action, ev.Rows), nil
 }
 
+// WaitUntilPos blocks, polling canal's own in-memory consumption
+// progress, until it has consumed at least pos or timeout (in seconds)
+// elapses. Because it only reflects what this canal has read off the
+// wire so far, it's the right choice when waiting on canal itself (e.g.
+// "has my handler seen this write yet"). To wait on what the upstream
+// server has actually written, use CatchMasterPos instead.
 func (c *Canal) WaitUntilPos(pos mysql.Position, timeout int) error {
 	if timeout <= 0 {
 		timeout = 60
@@ -184,7 +183,21 @@ func (c *Canal) WaitUntilPos(pos mysql.Position, timeout int) error {
 	return nil
 }
 
+// CatchMasterPos waits until the master's current position has been
+// reached, the way gh-ost's MasterPosWait does: it asks the server itself
+// to block via MASTER_POS_WAIT (or, in GTID mode, CatchMasterGTID's
+// WAIT_FOR_EXECUTED_GTID_SET), so the wait reflects what the server has
+// actually written rather than only what this canal has consumed, and
+// doesn't burn a goroutine busy-polling to find out.
 func (c *Canal) CatchMasterPos(timeout int) error {
+	if timeout <= 0 {
+		timeout = 60
+	}
+
+	if c.master.GTID() != nil {
+		return c.CatchMasterGTID(timeout)
+	}
+
 	rr, err := c.Execute("SHOW MASTER STATUS")
 	if err != nil {
 		return errors.Trace(err)
@@ -193,5 +206,80 @@ func (c *Canal) CatchMasterPos(timeout int) error {
 	name, _ := rr.GetString(0, 0)
 	pos, _ := rr.GetInt(0, 1)
 
-	return c.WaitUntilPos(mysql.Position{name, uint32(pos)}, timeout)
+	return c.masterPosWait(mysql.Position{Name: name, Pos: uint32(pos)}, timeout)
+}
+
+// masterPosWait issues SELECT MASTER_POS_WAIT(file, pos, timeout) on the
+// master connection and reports an error if it times out.
+func (c *Canal) masterPosWait(pos mysql.Position, timeout int) error {
+	rr, err := c.Execute(fmt.Sprintf("SELECT MASTER_POS_WAIT('%s', %d, %d)",
+		escapeString(pos.Name), pos.Pos, timeout))
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// MASTER_POS_WAIT returns the number of log events waited for, or
+	// -1 on timeout (and NULL if replication wasn't running / the
+	// position was invalid, which GetInt surfaces as an error).
+	n, err := rr.GetInt(0, 0)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if n < 0 {
+		return errors.Errorf("wait master pos %v timeout", pos)
+	}
+	return nil
+}
+
+// waitForExecutedGTIDSet waits, server-side, for set to be applied.
+// MySQL and MariaDB have no common function for this: MySQL exposes
+// WAIT_FOR_EXECUTED_GTID_SET, MariaDB MASTER_GTID_WAIT, and even their
+// return conventions on timeout differ, so we dispatch on c.cfg.Flavor
+// the same way mysql.ParseGTIDSet does.
+func (c *Canal) waitForExecutedGTIDSet(set mysql.GTIDSet, timeout int) error {
+	if c.cfg.Flavor == mysql.MariaDBFlavor {
+		return c.masterGTIDWait(set, timeout)
+	}
+
+	rr, err := c.Execute(fmt.Sprintf("SELECT WAIT_FOR_EXECUTED_GTID_SET('%s', %d)",
+		escapeString(set.String()), timeout))
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// WAIT_FOR_EXECUTED_GTID_SET returns 0 once the set has been
+	// applied, 1 on timeout.
+	n, err := rr.GetInt(0, 0)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if n != 0 {
+		return errors.Errorf("wait GTID set %v timeout", set)
+	}
+	return nil
+}
+
+// masterGTIDWait issues SELECT MASTER_GTID_WAIT(set, timeout), the
+// MariaDB equivalent of WAIT_FOR_EXECUTED_GTID_SET.
+func (c *Canal) masterGTIDWait(set mysql.GTIDSet, timeout int) error {
+	rr, err := c.Execute(fmt.Sprintf("SELECT MASTER_GTID_WAIT('%s', %d)",
+		escapeString(set.String()), timeout))
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// MASTER_GTID_WAIT returns 0 once the set has been applied, -1 on
+	// timeout.
+	n, err := rr.GetInt(0, 0)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if n != 0 {
+		return errors.Errorf("wait GTID set %v timeout", set)
+	}
+	return nil
+}
+
+func escapeString(s string) string {
+	return strings.Replace(s, "'", "''", -1)
 }
@@ -0,0 +1,71 @@
+package canal
+
+import (
+	"github.com/juju/errors"
+	"github.com/siddontang/go-mysql/mysql"
+)
+
+// Transaction is every row change made between a BEGIN and its matching
+// XIDEvent, delivered as a single atomic unit so sinks (Elasticsearch,
+// Kafka, ...) can apply or deduplicate them together instead of seeing
+// each row mutation in isolation.
+type Transaction struct {
+	GTID       mysql.GTIDSet
+	Pos        mysql.Position
+	Statements []*RowsEvent
+	Timestamp  uint32
+}
+
+// TransactionHandler is notified once per completed transaction when
+// Config.UseTransaction is enabled. It is mutually exclusive with the
+// per-row RowsEventHandler path for the same rows.
+type TransactionHandler interface {
+	Handle(t *Transaction) error
+}
+
+// RegisterTransactionHandler adds h to the handlers notified of every
+// completed Transaction.
+func (c *Canal) RegisterTransactionHandler(h TransactionHandler) {
+	c.transactionHandlers = append(c.transactionHandlers, h)
+}
+
+func (c *Canal) travelTransactionHandler(t *Transaction) error {
+	for _, h := range c.transactionHandlers {
+		if err := h.Handle(t); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// appendTransactionRowsEvent buffers ev into the in-flight transaction,
+// lazily starting one if none is open yet -- covering both the normal
+// QueryEvent("BEGIN") case and the GTID-mode autocommit case where a
+// single-statement transaction has no BEGIN at all.
+func (c *Canal) appendTransactionRowsEvent(ev *RowsEvent, timestamp uint32) {
+	if c.currentTxn == nil {
+		c.currentTxn = &Transaction{Timestamp: timestamp}
+	}
+	c.currentTxn.Statements = append(c.currentTxn.Statements, ev)
+}
+
+// commitTransaction delivers and clears the in-flight transaction, if
+// any, once its XIDEvent is reached.
+func (c *Canal) commitTransaction(pos mysql.Position) error {
+	if c.currentTxn == nil {
+		return nil
+	}
+
+	txn := c.currentTxn
+	c.currentTxn = nil
+	txn.Pos = pos
+	if c.pendingGTIDSet != nil {
+		// Clone: c.pendingGTIDSet keeps mutating as later transactions
+		// are folded in, but txn is handed to TransactionHandlers that
+		// may retain it past this call (e.g. to checkpoint after an
+		// async ack).
+		txn.GTID = c.pendingGTIDSet.Clone()
+	}
+
+	return c.travelTransactionHandler(txn)
+}
@@ -0,0 +1,78 @@
+package canal
+
+import (
+	"testing"
+
+	"github.com/siddontang/go-mysql/mysql"
+)
+
+type recordingTransactionHandler struct {
+	got []*Transaction
+}
+
+func (h *recordingTransactionHandler) Handle(t *Transaction) error {
+	h.got = append(h.got, t)
+	return nil
+}
+
+// TestTransactionDeliveredAtomicallyOnXID drives appendTransactionRowsEvent
+// (what onRowsEvent buffers through) and the real onXIDEvent, the same
+// BEGIN-rows-XID sequence a QueryEvent("BEGIN") followed by RowsEvents and
+// an XIDEvent produces on the wire.
+func TestTransactionDeliveredAtomicallyOnXID(t *testing.T) {
+	var c Canal
+	c.master = &MasterInfo{}
+	h := &recordingTransactionHandler{}
+	c.RegisterTransactionHandler(h)
+
+	set, err := mysql.ParseGTIDSet(mysql.MySQLFlavor, "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pendingGTIDSet = set
+
+	row1 := &RowsEvent{Action: InsertAction}
+	row2 := &RowsEvent{Action: InsertAction}
+	c.appendTransactionRowsEvent(row1, 100)
+	c.appendTransactionRowsEvent(row2, 100)
+
+	if c.currentTxn == nil || len(c.currentTxn.Statements) != 2 {
+		t.Fatalf("expected 2 buffered statements before the XIDEvent, got %+v", c.currentTxn)
+	}
+
+	state := &BinlogEventState{Pos: mysql.Position{Name: "mysql-bin.000001", Pos: 500}, ForceSave: true}
+	if err := c.onXIDEvent(nil, state); err != nil {
+		t.Fatalf("onXIDEvent: unexpected error: %v", err)
+	}
+
+	if c.currentTxn != nil {
+		t.Fatalf("currentTxn not cleared after commit: %+v", c.currentTxn)
+	}
+	if len(h.got) != 1 {
+		t.Fatalf("expected exactly one delivered Transaction, got %d", len(h.got))
+	}
+
+	txn := h.got[0]
+	if len(txn.Statements) != 2 || txn.Statements[0] != row1 || txn.Statements[1] != row2 {
+		t.Fatalf("Transaction.Statements = %+v, want [row1 row2]", txn.Statements)
+	}
+	if txn.Pos != state.Pos {
+		t.Fatalf("Transaction.Pos = %+v, want %+v", txn.Pos, state.Pos)
+	}
+	if want := "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5"; txn.GTID == nil || txn.GTID.String() != want {
+		t.Fatalf("Transaction.GTID = %v, want %v", txn.GTID, want)
+	}
+}
+
+func TestCommitTransactionNoopWithoutOpenTransaction(t *testing.T) {
+	var c Canal
+	h := &recordingTransactionHandler{}
+	c.RegisterTransactionHandler(h)
+
+	if err := c.commitTransaction(mysql.Position{Name: "mysql-bin.000001", Pos: 4}); err != nil {
+		t.Fatalf("commitTransaction: unexpected error: %v", err)
+	}
+	if len(h.got) != 0 {
+		t.Fatalf("TransactionHandler invoked with no open transaction: %+v", h.got)
+	}
+}
@@ -0,0 +1,69 @@
+package mysql
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// GTIDFlavor identifies which GTID encoding a GTIDSet uses, since MySQL
+// and MariaDB disagree on both the wire format and the set semantics.
+type GTIDFlavor string
+
+const (
+	MySQLFlavor   GTIDFlavor = "mysql"
+	MariaDBFlavor GTIDFlavor = "mariadb"
+)
+
+// GTIDSet is a position abstraction that complements Position: it tracks
+// replication progress as a set of executed transaction ids instead of a
+// (file, offset) pair, so it stays valid across failovers where file/pos
+// is not preserved.
+type GTIDSet interface {
+	// String returns the textual form used by SHOW MASTER STATUS /
+	// gtid_executed, e.g. "server-uuid:1-5" or "0-1-100".
+	String() string
+
+	// Encode serializes the set for persisting via MasterInfoHandler.
+	Encode() []byte
+
+	// Equal reports whether two sets cover exactly the same transactions.
+	Equal(o GTIDSet) bool
+
+	// Contain reports whether o is a subset of this set, i.e. every
+	// transaction in o has already been applied.
+	Contain(o GTIDSet) bool
+
+	// Update merges the GTIDs described by gtidStr into the set.
+	Update(gtidStr string) error
+
+	// Clone returns a deep copy, so the original can keep being mutated
+	// via Update without affecting anything that retains the copy past
+	// the call that handed it out.
+	Clone() GTIDSet
+}
+
+// ParseGTIDSet parses a GTID set string for the given flavor. flavor must
+// be MySQLFlavor or MariaDBFlavor.
+func ParseGTIDSet(flavor GTIDFlavor, gtidStr string) (GTIDSet, error) {
+	switch flavor {
+	case MySQLFlavor:
+		return ParseMysqlGTIDSet(gtidStr)
+	case MariaDBFlavor:
+		return ParseMariadbGTIDSet(gtidStr)
+	default:
+		return nil, errors.Errorf("unsupported flavor %s", flavor)
+	}
+}
+
+func splitNonEmpty(s string, sep string) []string {
+	parts := strings.Split(s, sep)
+	ret := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if len(p) > 0 {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// MariadbGTID is a single domain-server-sequence triple, e.g. "0-1-100".
+type MariadbGTID struct {
+	DomainID       uint32
+	ServerID       uint32
+	SequenceNumber uint64
+}
+
+func parseMariadbGTID(gtidStr string) (MariadbGTID, error) {
+	parts := strings.Split(strings.TrimSpace(gtidStr), "-")
+	if len(parts) != 3 {
+		return MariadbGTID{}, errors.Errorf("invalid mariadb gtid %s", gtidStr)
+	}
+
+	domainID, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return MariadbGTID{}, errors.Trace(err)
+	}
+	serverID, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return MariadbGTID{}, errors.Trace(err)
+	}
+	seq, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return MariadbGTID{}, errors.Trace(err)
+	}
+
+	return MariadbGTID{
+		DomainID:       uint32(domainID),
+		ServerID:       uint32(serverID),
+		SequenceNumber: seq,
+	}, nil
+}
+
+func (g MariadbGTID) String() string {
+	return fmt.Sprintf("%d-%d-%d", g.DomainID, g.ServerID, g.SequenceNumber)
+}
+
+// MariadbGTIDSet implements GTIDSet for MariaDB. Unlike MySQL, only the
+// most recent GTID per replication domain matters: a higher sequence
+// number from any server in a domain supersedes earlier ones.
+type MariadbGTIDSet struct {
+	Sets map[uint32]MariadbGTID
+}
+
+// ParseMariadbGTIDSet parses a comma separated list of domain-server-seq
+// triples, the format returned by @@gtid_current_pos / @@gtid_slave_pos.
+func ParseMariadbGTIDSet(gtidStr string) (GTIDSet, error) {
+	s := &MariadbGTIDSet{Sets: make(map[uint32]MariadbGTID)}
+
+	gtidStr = strings.TrimSpace(gtidStr)
+	if len(gtidStr) == 0 {
+		return s, nil
+	}
+
+	if err := s.Update(gtidStr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return s, nil
+}
+
+func (s *MariadbGTIDSet) Update(gtidStr string) error {
+	for _, part := range splitNonEmpty(gtidStr, ",") {
+		gtid, err := parseMariadbGTID(part)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		if cur, ok := s.Sets[gtid.DomainID]; !ok || gtid.SequenceNumber > cur.SequenceNumber {
+			s.Sets[gtid.DomainID] = gtid
+		}
+	}
+	return nil
+}
+
+func (s *MariadbGTIDSet) String() string {
+	domains := make([]uint32, 0, len(s.Sets))
+	for d := range s.Sets {
+		domains = append(domains, d)
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i] < domains[j] })
+
+	strs := make([]string, 0, len(domains))
+	for _, d := range domains {
+		strs = append(strs, s.Sets[d].String())
+	}
+	return strings.Join(strs, ",")
+}
+
+func (s *MariadbGTIDSet) Encode() []byte {
+	return []byte(s.String())
+}
+
+func (s *MariadbGTIDSet) Clone() GTIDSet {
+	clone := &MariadbGTIDSet{Sets: make(map[uint32]MariadbGTID, len(s.Sets))}
+	for domain, gtid := range s.Sets {
+		clone.Sets[domain] = gtid
+	}
+	return clone
+}
+
+func (s *MariadbGTIDSet) Equal(o GTIDSet) bool {
+	other, ok := o.(*MariadbGTIDSet)
+	if !ok {
+		return false
+	}
+	return s.Contain(other) && other.Contain(s)
+}
+
+func (s *MariadbGTIDSet) Contain(o GTIDSet) bool {
+	other, ok := o.(*MariadbGTIDSet)
+	if !ok {
+		return false
+	}
+
+	for domain, gtid := range other.Sets {
+		mine, ok := s.Sets[domain]
+		if !ok || mine.SequenceNumber < gtid.SequenceNumber {
+			return false
+		}
+	}
+	return true
+}
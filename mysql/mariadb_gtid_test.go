@@ -0,0 +1,126 @@
+package mysql
+
+import "testing"
+
+func TestParseMariadbGTIDSet(t *testing.T) {
+	cases := []struct {
+		name    string
+		gtidStr string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "single gtid",
+			gtidStr: "0-1-100",
+			want:    "0-1-100",
+		},
+		{
+			name:    "multiple domains sorted",
+			gtidStr: "1-2-200,0-1-100",
+			want:    "0-1-100,1-2-200",
+		},
+		{
+			name:    "same domain keeps highest sequence",
+			gtidStr: "0-1-100,0-2-50",
+			want:    "0-1-100",
+		},
+		{
+			name:    "same domain higher sequence from later server wins",
+			gtidStr: "0-1-100,0-2-150",
+			want:    "0-2-150",
+		},
+		{
+			name:    "empty",
+			gtidStr: "",
+			want:    "",
+		},
+		{
+			name:    "too few parts",
+			gtidStr: "0-1",
+			wantErr: true,
+		},
+		{
+			name:    "non numeric domain",
+			gtidStr: "x-1-100",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := ParseMariadbGTIDSet(c.gtidStr)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMariadbGTIDSet(%q): expected error, got none", c.gtidStr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMariadbGTIDSet(%q): unexpected error: %v", c.gtidStr, err)
+			}
+			if got := set.String(); got != c.want {
+				t.Fatalf("ParseMariadbGTIDSet(%q).String() = %q, want %q", c.gtidStr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMariadbGTIDSetContain(t *testing.T) {
+	full, err := ParseMariadbGTIDSet("0-1-100,1-1-50")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		gtidStr string
+		want    bool
+	}{
+		{name: "exact", gtidStr: "0-1-100,1-1-50", want: true},
+		{name: "lower sequence contained", gtidStr: "0-1-50", want: true},
+		{name: "higher sequence not contained", gtidStr: "0-1-101", want: false},
+		{name: "unknown domain not contained", gtidStr: "2-1-1", want: false},
+		{name: "empty always contained", gtidStr: "", want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			other, err := ParseMariadbGTIDSet(c.gtidStr)
+			if err != nil {
+				t.Fatalf("ParseMariadbGTIDSet(%q): unexpected error: %v", c.gtidStr, err)
+			}
+			if got := full.Contain(other); got != c.want {
+				t.Fatalf("Contain(%q) = %v, want %v", c.gtidStr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMariadbGTIDSetUpdate(t *testing.T) {
+	set, err := ParseMariadbGTIDSet("0-1-100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := set.Update("0-1-150"); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if want, got := "0-1-150", set.String(); got != want {
+		t.Fatalf("after Update, String() = %q, want %q", got, want)
+	}
+}
+
+func TestMariadbGTIDSetCloneIsolated(t *testing.T) {
+	set, err := ParseMariadbGTIDSet("0-1-100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clone := set.Clone()
+	if err := set.Update("0-1-150"); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	if want, got := "0-1-100", clone.String(); got != want {
+		t.Fatalf("clone mutated by later Update on original: String() = %q, want %q", got, want)
+	}
+}
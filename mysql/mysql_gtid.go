@@ -0,0 +1,186 @@
+package mysql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// mysqlInterval is a closed-open range [Start, Stop) of transaction
+// sequence numbers, matching the half-open convention MySQL itself uses
+// internally for gtid_executed intervals.
+type mysqlInterval struct {
+	Start int64
+	Stop  int64
+}
+
+// mysqlUUIDSet holds every interval recorded for a single server UUID.
+type mysqlUUIDSet struct {
+	SID       string
+	Intervals []mysqlInterval
+}
+
+// MysqlGTIDSet implements GTIDSet using MySQL's UUID:interval[:interval...]
+// encoding, e.g. "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:11-13".
+type MysqlGTIDSet struct {
+	Sets map[string]*mysqlUUIDSet
+}
+
+// ParseMysqlGTIDSet parses a comma separated list of UUID:interval groups.
+func ParseMysqlGTIDSet(gtidStr string) (GTIDSet, error) {
+	s := &MysqlGTIDSet{Sets: make(map[string]*mysqlUUIDSet)}
+
+	gtidStr = strings.TrimSpace(gtidStr)
+	if len(gtidStr) == 0 {
+		return s, nil
+	}
+
+	for _, group := range splitNonEmpty(gtidStr, ",") {
+		if err := s.Update(group); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return s, nil
+}
+
+func (s *MysqlGTIDSet) Update(gtidStr string) error {
+	for _, group := range splitNonEmpty(gtidStr, ",") {
+		parts := strings.Split(group, ":")
+		if len(parts) < 2 {
+			return errors.Errorf("invalid mysql gtid %s", group)
+		}
+
+		sid := strings.ToUpper(parts[0])
+		uuidSet, ok := s.Sets[sid]
+		if !ok {
+			uuidSet = &mysqlUUIDSet{SID: sid}
+			s.Sets[sid] = uuidSet
+		}
+
+		for _, rng := range parts[1:] {
+			interval, err := parseMysqlInterval(rng)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			uuidSet.Intervals = addMysqlInterval(uuidSet.Intervals, interval)
+		}
+	}
+	return nil
+}
+
+func parseMysqlInterval(rng string) (mysqlInterval, error) {
+	nums := strings.Split(rng, "-")
+	start, err := strconv.ParseInt(nums[0], 10, 64)
+	if err != nil {
+		return mysqlInterval{}, errors.Trace(err)
+	}
+
+	stop := start
+	if len(nums) == 2 {
+		stop, err = strconv.ParseInt(nums[1], 10, 64)
+		if err != nil {
+			return mysqlInterval{}, errors.Trace(err)
+		}
+	}
+	// MySQL reports interval ends inclusive; we keep Stop exclusive
+	// internally so merging adjacent intervals is a simple comparison.
+	return mysqlInterval{Start: start, Stop: stop + 1}, nil
+}
+
+func addMysqlInterval(intervals []mysqlInterval, add mysqlInterval) []mysqlInterval {
+	intervals = append(intervals, add)
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start < intervals[j].Start })
+
+	merged := intervals[:1]
+	for _, iv := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if iv.Start <= last.Stop {
+			if iv.Stop > last.Stop {
+				last.Stop = iv.Stop
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+func (s *MysqlGTIDSet) String() string {
+	sids := make([]string, 0, len(s.Sets))
+	for sid := range s.Sets {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+
+	groups := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		uuidSet := s.Sets[sid]
+		ranges := make([]string, 0, len(uuidSet.Intervals))
+		for _, iv := range uuidSet.Intervals {
+			if iv.Stop-iv.Start == 1 {
+				ranges = append(ranges, strconv.FormatInt(iv.Start, 10))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", iv.Start, iv.Stop-1))
+			}
+		}
+		groups = append(groups, fmt.Sprintf("%s:%s", sid, strings.Join(ranges, ":")))
+	}
+	return strings.Join(groups, ",")
+}
+
+func (s *MysqlGTIDSet) Encode() []byte {
+	return []byte(s.String())
+}
+
+func (s *MysqlGTIDSet) Clone() GTIDSet {
+	clone := &MysqlGTIDSet{Sets: make(map[string]*mysqlUUIDSet, len(s.Sets))}
+	for sid, uuidSet := range s.Sets {
+		intervals := make([]mysqlInterval, len(uuidSet.Intervals))
+		copy(intervals, uuidSet.Intervals)
+		clone.Sets[sid] = &mysqlUUIDSet{SID: uuidSet.SID, Intervals: intervals}
+	}
+	return clone
+}
+
+func (s *MysqlGTIDSet) Equal(o GTIDSet) bool {
+	other, ok := o.(*MysqlGTIDSet)
+	if !ok {
+		return false
+	}
+	return s.Contain(other) && other.Contain(s)
+}
+
+func (s *MysqlGTIDSet) Contain(o GTIDSet) bool {
+	other, ok := o.(*MysqlGTIDSet)
+	if !ok {
+		return false
+	}
+
+	for sid, uuidSet := range other.Sets {
+		mine, ok := s.Sets[sid]
+		if !ok {
+			if len(uuidSet.Intervals) > 0 {
+				return false
+			}
+			continue
+		}
+		for _, iv := range uuidSet.Intervals {
+			if !mysqlIntervalsContain(mine.Intervals, iv) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mysqlIntervalsContain(intervals []mysqlInterval, target mysqlInterval) bool {
+	for _, iv := range intervals {
+		if iv.Start <= target.Start && target.Stop <= iv.Stop {
+			return true
+		}
+	}
+	return false
+}
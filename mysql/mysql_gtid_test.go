@@ -0,0 +1,142 @@
+package mysql
+
+import "testing"
+
+func TestParseMysqlGTIDSet(t *testing.T) {
+	cases := []struct {
+		name    string
+		gtidStr string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "single interval",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5",
+		},
+		{
+			name:    "single transaction",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:5",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:5",
+		},
+		{
+			name:    "multiple disjoint intervals",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:11-13",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:11-13",
+		},
+		{
+			name:    "adjacent intervals merge",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:6-10",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-10",
+		},
+		{
+			name:    "overlapping intervals merge",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:4-10",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-10",
+		},
+		{
+			name:    "out of order intervals sort before merging",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:11-13:1-5",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:11-13",
+		},
+		{
+			name:    "multiple uuids sorted by sid",
+			gtidStr: "B9B4712A-DEB0-11E6-BE5E-000C29AC2CA6:1-5,3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5",
+			want:    "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5,B9B4712A-DEB0-11E6-BE5E-000C29AC2CA6:1-5",
+		},
+		{
+			name:    "empty",
+			gtidStr: "",
+			want:    "",
+		},
+		{
+			name:    "missing interval",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562",
+			wantErr: true,
+		},
+		{
+			name:    "non numeric interval",
+			gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:x-5",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := ParseMysqlGTIDSet(c.gtidStr)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMysqlGTIDSet(%q): expected error, got none", c.gtidStr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMysqlGTIDSet(%q): unexpected error: %v", c.gtidStr, err)
+			}
+			if got := set.String(); got != c.want {
+				t.Fatalf("ParseMysqlGTIDSet(%q).String() = %q, want %q", c.gtidStr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMysqlGTIDSetContain(t *testing.T) {
+	full, err := ParseMysqlGTIDSet("3E11FA47-71CA-11E1-9E33-C80AA9429562:1-10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		gtidStr string
+		want    bool
+	}{
+		{name: "exact", gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-10", want: true},
+		{name: "subset", gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:2-5", want: true},
+		{name: "single contained transaction", gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:10", want: true},
+		{name: "exceeds upper bound", gtidStr: "3E11FA47-71CA-11E1-9E33-C80AA9429562:5-11", want: false},
+		{name: "unknown sid", gtidStr: "B9B4712A-DEB0-11E6-BE5E-000C29AC2CA6:1-5", want: false},
+		{name: "empty set always contained", gtidStr: "", want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			other, err := ParseMysqlGTIDSet(c.gtidStr)
+			if err != nil {
+				t.Fatalf("ParseMysqlGTIDSet(%q): unexpected error: %v", c.gtidStr, err)
+			}
+			if got := full.Contain(other); got != c.want {
+				t.Fatalf("Contain(%q) = %v, want %v", c.gtidStr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMysqlGTIDSetUpdate(t *testing.T) {
+	set, err := ParseMysqlGTIDSet("3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := set.Update("3E11FA47-71CA-11E1-9E33-C80AA9429562:6-10"); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if want, got := "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-10", set.String(); got != want {
+		t.Fatalf("after Update, String() = %q, want %q", got, want)
+	}
+}
+
+func TestMysqlGTIDSetCloneIsolated(t *testing.T) {
+	set, err := ParseMysqlGTIDSet("3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clone := set.Clone()
+	if err := set.Update("3E11FA47-71CA-11E1-9E33-C80AA9429562:6-20"); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	if want, got := "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5", clone.String(); got != want {
+		t.Fatalf("clone mutated by later Update on original: String() = %q, want %q", got, want)
+	}
+}